@@ -0,0 +1,101 @@
+package gobs
+
+import "sync"
+
+// Future tracks the completion of a job submitted via SubmitFunc. It is the
+// generics-based counterpart to Status for jobs that return a typed result.
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// Wait blocks until the associated job has terminated. It returns the job's
+// result and error.
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// SubmitFunc submits fn to p and returns a Future that can be used to
+// retrieve fn's typed result once it completes. Like Submit, SubmitFunc
+// blocks until the pool's concurrency setting allows fn to start running,
+// then runs it in a new goroutine.
+func SubmitFunc[T any](p *Pool, fn func() (T, error)) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	if err := p.acquire(p.context()); err != nil {
+		f.err = err
+		close(f.done)
+		return f
+	}
+	go func() {
+		f.err = p.runJob(func() error {
+			var err error
+			f.val, err = fn()
+			return err
+		})
+		p.finish(f.err)
+		close(f.done)
+	}()
+	return f
+}
+
+// BatchFunc is the generics-based counterpart to Batch for functions that
+// return a typed result: it aggregates the results of functions submitted
+// via Submit into a slice, alongside a MultiError collecting their errors.
+type BatchFunc[T any] struct {
+	p    *Pool
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	vals []T
+	me   *multiErr
+}
+
+// NewBatchFunc creates a new BatchFunc of results of type T, bound to p.
+func NewBatchFunc[T any](p *Pool) *BatchFunc[T] {
+	return &BatchFunc[T]{p: p, me: &multiErr{}}
+}
+
+// Submit adds fn to the batch. Submit blocks until the pool's concurrency
+// setting allows fn to start running, then runs it in a new goroutine. Use
+// BatchFunc.Wait to retrieve the aggregated results.
+func (b *BatchFunc[T]) Submit(fn func() (T, error)) {
+	b.wg.Add(1)
+	if err := b.p.acquire(b.p.context()); err != nil {
+		b.me.add(err)
+		b.wg.Done()
+		return
+	}
+	go func() {
+		defer b.wg.Done()
+		var val T
+		err := b.p.runJob(func() error {
+			var runErr error
+			val, runErr = fn()
+			return runErr
+		})
+		b.p.finish(err)
+		if err != nil {
+			b.me.add(err)
+			return
+		}
+		b.mu.Lock()
+		b.vals = append(b.vals, val)
+		b.mu.Unlock()
+	}()
+}
+
+// Wait blocks until all functions submitted to the batch have completed.
+// Once Wait has been called, no further functions should be submitted to the
+// batch.
+//
+// Wait returns the results of the functions that succeeded, in no
+// particular order, alongside a MultiError collecting the errors of those
+// that failed.
+func (b *BatchFunc[T]) Wait() ([]T, error) {
+	b.wg.Wait()
+	if len(b.me.errs) > 0 {
+		return b.vals, b.me
+	}
+	return b.vals, nil
+}
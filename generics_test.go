@@ -0,0 +1,39 @@
+package gobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitFunc(t *testing.T) {
+	pool := NewPool(2)
+	f := SubmitFunc(pool, func() (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 42, nil
+	})
+	val, err := f.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, val)
+
+	f2 := SubmitFunc(pool, func() (int, error) {
+		return 0, assert.AnError
+	})
+	val, err = f2.Wait()
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, 0, val)
+}
+
+func TestBatchFunc(t *testing.T) {
+	pool := NewPool(2)
+	batch := NewBatchFunc[int](pool)
+	batch.Submit(func() (int, error) { return 1, nil })
+	batch.Submit(func() (int, error) { return 2, nil })
+	batch.Submit(func() (int, error) { return 0, assert.AnError })
+
+	vals, err := batch.Wait()
+	assert.Error(t, err)
+	assert.Len(t, err.(MultiError).Errors(), 1)
+	assert.ElementsMatch(t, []int{1, 2}, vals)
+}
@@ -5,13 +5,23 @@
 package gobs
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Job is a unit of work that returns a non-nil error in case of failure
 type Job func() error
 
+// JobCtx is a Job that receives the context in effect for the Pool or Batch
+// it was submitted to. Jobs submitted to a Pool or Batch created with
+// PoolWithContext or BatchWithContext should observe ctx.Done() so they can
+// return early once that context is canceled.
+type JobCtx func(ctx context.Context) error
+
 // Status tracks the completion of a Job
 type Status struct {
 	done chan struct{}
@@ -27,50 +37,383 @@ func (s *Status) Wait() error {
 
 // Pool is a worker pool that accepts a bounded number of Jobs
 type Pool struct {
-	concurrency int
-	jobs        chan struct{}
+	mu           sync.Mutex
+	cond         *sync.Cond
+	limit        int
+	running      int
+	queued       int
+	ctx          context.Context
+	panicHandler PanicHandler
+	obs          Observer
+
+	submitted int64
+	completed int64
+	failed    int64
+}
+
+// Observer receives lifecycle callbacks for jobs running through a Pool,
+// enabling integration with metrics systems such as Prometheus or
+// OpenTelemetry without this package importing them directly. Implementations
+// must be safe for concurrent use, since callbacks are invoked from the
+// goroutines submitting and running jobs. Callbacks are never invoked while
+// the Pool holds its internal lock, so it is safe for an Observer to call
+// back into the Pool, e.g. Stats(), from within any of these methods.
+type Observer interface {
+	// OnSubmit is called when a job is submitted to the pool, before it
+	// waits for a concurrency slot.
+	OnSubmit()
+	// OnStart is called when a job starts running, after it has acquired a
+	// concurrency slot.
+	OnStart()
+	// OnFinish is called when a job has finished running, with the error it
+	// returned (nil on success, possibly a *PanicError).
+	OnFinish(err error)
+	// OnWaitDuration is called with the time a job spent waiting for a
+	// concurrency slot, once it either starts running or gives up due to
+	// context cancellation. It is not called for jobs submitted via
+	// TrySubmit, which never wait for a slot.
+	OnWaitDuration(d time.Duration)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) OnSubmit()                      {}
+func (noopObserver) OnStart()                       {}
+func (noopObserver) OnFinish(err error)             {}
+func (noopObserver) OnWaitDuration(d time.Duration) {}
+
+// observer returns the Pool's Observer, defaulting to a no-op implementation
+// for Pools created without WithObserver.
+func (p *Pool) observer() Observer {
+	if p.obs != nil {
+		return p.obs
+	}
+	return noopObserver{}
+}
+
+// WithObserver registers an Observer that is notified of a Pool's job
+// lifecycle events.
+func WithObserver(o Observer) Option {
+	return func(p *Pool) {
+		p.obs = o
+	}
+}
+
+// Stats is a snapshot of a Pool's activity, returned by Pool.Stats().
+type Stats struct {
+	Running   int
+	Queued    int
+	Submitted int64
+	Completed int64
+	Failed    int64
+}
+
+// Stats returns a snapshot of the Pool's current activity: how many jobs are
+// running and queued waiting for a slot right now, and how many have been
+// submitted, completed and failed over the Pool's lifetime. Submitted only
+// counts jobs that were given a concurrency slot: submissions refused by
+// TrySubmit or abandoned because the context was canceled are not counted.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	running, queued := p.running, p.queued
+	p.mu.Unlock()
+	return Stats{
+		Running:   running,
+		Queued:    queued,
+		Submitted: atomic.LoadInt64(&p.submitted),
+		Completed: atomic.LoadInt64(&p.completed),
+		Failed:    atomic.LoadInt64(&p.failed),
+	}
+}
+
+// PanicHandler is called with the value recovered from a panicking Job. It
+// runs on the worker goroutine, inside the deferred recover, so the panicking
+// Job's frames are still on the stack. It should return the
+// error to store on the Job's Status; the default handler, used when no
+// Option overrides it, wraps the value in a PanicError.
+type PanicHandler func(recovered interface{}) error
+
+// PanicError is the error stored on a Job's Status when the Job panicked.
+// It carries both the recovered value and a stack trace captured at the
+// point of the panic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error is the standard error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+func defaultPanicHandler(recovered interface{}) error {
+	return &PanicError{Value: recovered, Stack: debug.Stack()}
+}
+
+// Option configures optional Pool behavior. Options are applied, in order,
+// by NewPool and PoolWithContext.
+type Option func(*Pool)
+
+// WithPanicHandler overrides how a Pool recovers from a panicking Job. By
+// default, a panicking Job's slot is still released and its Status.Wait
+// returns a *PanicError rather than crashing the process; WithPanicHandler
+// lets callers customize that conversion, e.g. to log the panic in addition
+// to reporting it as an error.
+func WithPanicHandler(h PanicHandler) Option {
+	return func(p *Pool) {
+		p.panicHandler = h
+	}
+}
+
+// runJob runs run, recovering from any panic via the Pool's PanicHandler so
+// that a panicking Job can neither crash the process nor leak its
+// concurrency slot.
+func (p *Pool) runJob(run func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = p.panicHandler(r)
+		}
+	}()
+	return run()
+}
+
+// context returns the context in effect for the Pool, defaulting to
+// context.Background() for Pools created with NewPool.
+func (p *Pool) context() context.Context {
+	if p.ctx != nil {
+		return p.ctx
+	}
+	return context.Background()
+}
+
+// acquire blocks until a concurrency slot is available or ctx is canceled,
+// whichever occurs first.
+func (p *Pool) acquire(ctx context.Context) error {
+	p.observer().OnSubmit()
+	start := time.Now()
+
+	// ctx.Done() is nil for a context that can never be canceled (e.g.
+	// context.Background(), used by Pools created without PoolWithContext):
+	// skip spawning a goroutine to watch a channel that will never fire.
+	if ctxDone := ctx.Done(); ctxDone != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctxDone:
+				p.mu.Lock()
+				p.cond.Broadcast()
+				p.mu.Unlock()
+			case <-done:
+			}
+		}()
+	}
+
+	p.mu.Lock()
+	p.queued++
+	for p.running >= p.limit {
+		if err := ctx.Err(); err != nil {
+			p.queued--
+			p.cond.Broadcast()
+			p.mu.Unlock()
+			p.observer().OnWaitDuration(time.Since(start))
+			return err
+		}
+		p.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		p.queued--
+		p.cond.Broadcast()
+		p.mu.Unlock()
+		p.observer().OnWaitDuration(time.Since(start))
+		return err
+	}
+	p.queued--
+	p.running++
+	atomic.AddInt64(&p.submitted, 1)
+	p.mu.Unlock()
+	p.observer().OnWaitDuration(time.Since(start))
+	p.observer().OnStart()
+	return nil
+}
+
+// tryAcquire reports whether a concurrency slot was available and, if so,
+// claims it without blocking. Like acquire, it refuses to claim a slot once
+// ctx is canceled.
+func (p *Pool) tryAcquire(ctx context.Context) bool {
+	p.observer().OnSubmit()
+	if ctx.Err() != nil {
+		return false
+	}
+	p.mu.Lock()
+	if p.running >= p.limit {
+		p.mu.Unlock()
+		return false
+	}
+	p.running++
+	atomic.AddInt64(&p.submitted, 1)
+	p.mu.Unlock()
+	p.observer().OnStart()
+	return true
+}
+
+// release frees a concurrency slot claimed by acquire or tryAcquire and
+// wakes up any goroutine waiting for one.
+func (p *Pool) release() {
+	p.mu.Lock()
+	p.running--
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// finish records a job's completion (updating Stats and notifying the
+// Observer) and releases its concurrency slot.
+func (p *Pool) finish(err error) {
+	atomic.AddInt64(&p.completed, 1)
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+	}
+	p.observer().OnFinish(err)
+	p.release()
 }
 
-// Stop blocks until all submitted jobs have completed, then frees all resources created
-// by the pool.
+// Resize changes the Pool's maximum concurrency at runtime. Growing the
+// limit immediately unblocks Submit/SubmitCtx calls that were queued on the
+// old limit. Shrinking it lets currently running jobs finish, but blocks new
+// admissions until the number of running jobs drops back under the new
+// limit.
+func (p *Pool) Resize(n int) {
+	if n < 1 {
+		panic("concurrency must be >= 1")
+	}
+	p.mu.Lock()
+	p.limit = n
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// Stop blocks until all submitted jobs have completed.
 //
-// Submitting a new Job to the Pool once Stop has been called will deadlock and/or panic.
-// Calling Stop more than once will deadlock and/or panic
+// Stop does not close the Pool: Jobs submitted after Stop has returned run as
+// usual, and calling Stop more than once is safe.
 func (p *Pool) Stop() {
-	//aquire all job slots (i.e. make sure none are occupied by a running job)
-	for i := 0; i < p.concurrency; i++ {
-		p.jobs <- struct{}{}
-	}
-	close(p.jobs)
-	for i := 0; i < p.concurrency; i++ {
-		<-p.jobs
+	p.mu.Lock()
+	for p.running > 0 || p.queued > 0 {
+		p.cond.Wait()
 	}
+	p.mu.Unlock()
 }
 
 // NewPool creates a worker pool garanteeing that no more than concurrency jobs will
 // be running at a given instant
-func NewPool(concurrency int) *Pool {
+func NewPool(concurrency int, opts ...Option) *Pool {
 	if concurrency < 1 {
 		panic("concurrency must be >= 1")
 	}
 	p := &Pool{
-		concurrency: concurrency,
+		limit: concurrency,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.panicHandler == nil {
+		p.panicHandler = defaultPanicHandler
 	}
-	p.jobs = make(chan struct{}, concurrency)
 	return p
 }
 
+// PoolWithContext creates a worker pool like NewPool, but ties it to ctx: once
+// ctx is canceled, Submit and SubmitCtx calls that are blocked waiting for a
+// concurrency slot return immediately with ctx.Err() instead of waiting for a
+// slot to free up. This lets callers abort in-flight submissions when, for
+// example, a client disconnects.
+//
+// PoolWithContext returns the Pool along with ctx, mirroring the
+// errgroup.WithContext calling convention.
+func PoolWithContext(ctx context.Context, concurrency int, opts ...Option) (*Pool, context.Context) {
+	p := NewPool(concurrency, opts...)
+	p.ctx = ctx
+	return p, ctx
+}
+
 // Submit adds a new job to the worker pool. Submit blocks until the pool's concurrency
 // setting allows the job to start running, then launches the job in a new goroutine.
 // It returns a Status that can be used to track the job completion and/or error
 func (p *Pool) Submit(job Job) *Status {
-	p.jobs <- struct{}{}
-	s := &Status{}
-	s.done = make(chan struct{})
+	return p.submit(p.context(), func(ctx context.Context) error { return job() })
+}
+
+// SubmitCtx is like Submit, but accepts a JobCtx that is passed the Pool's
+// context (or the Batch's derived context, when submitted via
+// Batch.SubmitCtx), allowing the job to observe cancellation.
+func (p *Pool) SubmitCtx(job JobCtx) *Status {
+	return p.submit(p.context(), job)
+}
+
+// TrySubmit attempts to add a new job to the worker pool without blocking. If
+// all concurrency slots are currently busy, or the Pool's context (see
+// PoolWithContext) is already canceled, it returns immediately with a nil
+// Status and false. Otherwise it behaves like Submit, launching the job in a
+// new goroutine and returning its Status alongside true.
+func (p *Pool) TrySubmit(job Job) (*Status, bool) {
+	if !p.tryAcquire(p.context()) {
+		return nil, false
+	}
+	s := &Status{done: make(chan struct{})}
 	go func() {
-		s.err = job()
+		s.err = p.runJob(job)
+		p.finish(s.err)
+		close(s.done)
+	}()
+	return s, true
+}
+
+// Go submits job to the pool like Submit, but does not allocate a Status or
+// done channel. Use it for high-throughput producers that only care about
+// batch-level completion (see Batch.Go) or don't need per-job wait
+// semantics: Go still blocks until a concurrency slot is available, but the
+// job's error (or recovered panic) is otherwise dropped on the floor.
+func (p *Pool) Go(job Job) {
+	p.goCtx(p.context(), func(ctx context.Context) error { return job() })
+}
+
+// GoCtx is like Go, but accepts a JobCtx so the job can observe the Pool's
+// (or Batch's) context.
+func (p *Pool) GoCtx(job JobCtx) {
+	p.goCtx(p.context(), job)
+}
+
+// goCtx is the common implementation backing Go and GoCtx. It reports
+// whether the job was admitted and will run in a new goroutine; callers that
+// need a completion signal regardless of admission (such as Batch.Go, which
+// must balance its WaitGroup even when ctx is already canceled) must act on
+// this return value rather than assume job always runs.
+func (p *Pool) goCtx(ctx context.Context, job JobCtx) bool {
+	if err := p.acquire(ctx); err != nil {
+		return false
+	}
+	go func() {
+		err := p.runJob(func() error { return job(ctx) })
+		p.finish(err)
+	}()
+	return true
+}
+
+// submit is the common implementation backing Submit and SubmitCtx. It
+// blocks until either a concurrency slot is acquired or ctx is canceled,
+// whichever happens first.
+func (p *Pool) submit(ctx context.Context, job JobCtx) *Status {
+	s := &Status{done: make(chan struct{})}
+	if err := p.acquire(ctx); err != nil {
+		s.err = err
+		close(s.done)
+		return s
+	}
+	go func() {
+		s.err = p.runJob(func() error { return job(ctx) })
+		p.finish(s.err)
 		close(s.done)
-		<-p.jobs
 	}()
 	return s
 }
@@ -115,9 +458,11 @@ type MultiError interface {
 // exposes a Wait() function which allows code to block while waiting for all jobs
 // of the batch to be completed
 type Batch struct {
-	p  *Pool
-	wg sync.WaitGroup
-	me *multiErr
+	p      *Pool
+	wg     sync.WaitGroup
+	me     *multiErr
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // Batch creates a new holder for a group of jobs to be run in the Pool.
@@ -129,24 +474,112 @@ func (p *Pool) Batch() *Batch {
 	return b
 }
 
+// BatchWithContext is to Batch what errgroup.WithContext is to a plain
+// errgroup.Group: it returns a Batch along with a context that is canceled as
+// soon as one of the Batch's jobs returns a non-nil error, or when Wait
+// returns, whichever occurs first. Pass the derived context into jobs
+// submitted via SubmitCtx so they can exit early once a sibling job fails.
+func (p *Pool) BatchWithContext(ctx context.Context) (*Batch, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &Batch{
+		p:      p,
+		me:     &multiErr{},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	return b, ctx
+}
+
+// context returns the context in effect for the Batch, falling back to its
+// Pool's context for a Batch created with Batch() rather than
+// BatchWithContext.
+func (b *Batch) context() context.Context {
+	if b.ctx != nil {
+		return b.ctx
+	}
+	return b.p.context()
+}
+
 // Submit adds a new job to the batch in the worker pool. Submit blocks until the pool's concurrency
 // setting allows the job to start running, then launches the job in a new goroutine.
 // To track the job's completion, use Batch.Wait()
 func (b *Batch) Submit(job Job) {
-	st := b.p.Submit(job)
+	b.SubmitCtx(func(ctx context.Context) error { return job() })
+}
+
+// SubmitCtx is like Submit, but accepts a JobCtx that is passed the Batch's
+// derived context (see BatchWithContext), allowing the job to observe
+// cancellation triggered by a sibling job's failure.
+func (b *Batch) SubmitCtx(job JobCtx) {
 	b.wg.Add(1)
-	go func() {
-		b.me.add(st.Wait())
+	ctx := b.context()
+	if err := b.p.acquire(ctx); err != nil {
+		// the job was never admitted (e.g. the Batch's context was already
+		// canceled by a sibling job's failure); balance the WaitGroup here.
+		// The job never ran, so there is nothing of its own to report: the
+		// cancellation that kept it from being admitted was already recorded
+		// by whichever job triggered it.
 		b.wg.Done()
+		return
+	}
+	go func() {
+		// b.wg.Done() runs after p.finish, so that once Wait returns the
+		// Pool's Stats and Observer already account for this job.
+		defer b.wg.Done()
+		err := b.p.runJob(func() error {
+			err := job(ctx)
+			if err != nil {
+				b.me.add(err)
+				if b.cancel != nil {
+					b.cancel()
+				}
+			}
+			return err
+		})
+		b.p.finish(err)
 	}()
 }
 
+// Go adds job to the batch. Like Submit, it allocates no per-job Status and
+// tracks the job through the Batch's WaitGroup directly. Job errors are
+// collected into the MultiError returned by Wait, and cancel the Batch's
+// derived context (see BatchWithContext).
+func (b *Batch) Go(job Job) {
+	b.wg.Add(1)
+	admitted := b.p.goCtx(b.context(), func(ctx context.Context) error {
+		defer b.wg.Done()
+		err := job()
+		if err != nil {
+			b.me.add(err)
+			if b.cancel != nil {
+				b.cancel()
+			}
+		}
+		return err
+	})
+	if !admitted {
+		// the job was never admitted (e.g. the Batch's context was already
+		// canceled by a sibling job's failure), so the goroutine above that
+		// would have called b.wg.Done() never runs; balance the WaitGroup
+		// here instead. The job never ran, so there is nothing of its own to
+		// report: the cancellation that kept it from being admitted was
+		// already recorded by whichever job triggered it.
+		b.wg.Done()
+	}
+}
+
 // Wait blocks until all job submitted to the batch have completed. Once Wait
 // has been called, no further jobs should be submitted to the batch.
 //
-// Wait returns a MultiError that can be used to inspect individual job errors
+// Wait returns nil if no job failed. Otherwise it returns a MultiError, even
+// for a single failure, whose Error() reports the first error encountered and
+// whose Errors() lists every job error, including those returned after the
+// Batch's context (for a Batch created with BatchWithContext) was canceled.
 func (b *Batch) Wait() error {
 	b.wg.Wait()
+	if b.cancel != nil {
+		b.cancel()
+	}
 	if len(b.me.errs) > 0 {
 		return b.me
 	}
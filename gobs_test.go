@@ -1,8 +1,10 @@
 package gobs
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -125,3 +127,309 @@ func TestBatch(t *testing.T) {
 	})
 	assert.NoError(t, batch.Wait())
 }
+
+func TestPoolWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, pctx := PoolWithContext(ctx, 1)
+	assert.Equal(t, ctx, pctx)
+
+	//occupy the only slot
+	pool.Submit(func() error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	//cancel before the slot frees up
+	cancel()
+	st := pool.Submit(func() error {
+		t.Fatal("job should not have run")
+		return nil
+	})
+	assert.Equal(t, context.Canceled, st.Wait())
+}
+
+func TestTrySubmit(t *testing.T) {
+	pool := NewPool(1)
+	release := make(chan struct{})
+	st, ok := pool.TrySubmit(func() error {
+		<-release
+		return nil
+	})
+	assert.True(t, ok)
+
+	//the single slot is busy, TrySubmit must not block
+	st2, ok := pool.TrySubmit(func() error { return nil })
+	assert.False(t, ok)
+	assert.Nil(t, st2)
+
+	close(release)
+	assert.NoError(t, st.Wait())
+
+	st3, ok := pool.TrySubmit(func() error { return nil })
+	assert.True(t, ok)
+	assert.NoError(t, st3.Wait())
+}
+
+func TestTrySubmitCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, _ := PoolWithContext(ctx, 1)
+	cancel()
+
+	st, ok := pool.TrySubmit(func() error {
+		t.Fatal("job should not have run")
+		return nil
+	})
+	assert.False(t, ok)
+	assert.Nil(t, st)
+}
+
+func TestResize(t *testing.T) {
+	pool := NewPool(1)
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+
+	for i := 0; i < 3; i++ {
+		go pool.Submit(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+	}
+
+	//only one job should have been able to start with concurrency 1
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, started, 1)
+
+	//growing the pool should immediately unblock the queued Submit callers
+	pool.Resize(3)
+	<-started
+	<-started
+	<-started
+	close(release)
+	pool.Stop()
+}
+
+func TestPanicRecovery(t *testing.T) {
+	pool := NewPool(1)
+	st := pool.Submit(func() error {
+		panic("boom")
+	})
+	err := st.Wait()
+	assert.Error(t, err)
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, "boom", pe.Value)
+
+	//the slot must have been released despite the panic
+	st2, ok := pool.TrySubmit(func() error { return nil })
+	assert.True(t, ok)
+	assert.NoError(t, st2.Wait())
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	var handled interface{}
+	pool := NewPool(1, WithPanicHandler(func(recovered interface{}) error {
+		handled = recovered
+		return fmt.Errorf("recovered: %v", recovered)
+	}))
+	st := pool.Submit(func() error {
+		panic("custom boom")
+	})
+	assert.EqualError(t, st.Wait(), "recovered: custom boom")
+	assert.Equal(t, "custom boom", handled)
+}
+
+func TestPoolGo(t *testing.T) {
+	pool := NewPool(2)
+	var wg sync.WaitGroup
+	var n int32
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		pool.Go(func() error {
+			defer wg.Done()
+			atomic.AddInt32(&n, 1)
+			return nil
+		})
+	}
+	wg.Wait()
+	assert.EqualValues(t, 5, atomic.LoadInt32(&n))
+	pool.Stop()
+}
+
+func TestBatchGo(t *testing.T) {
+	p := NewPool(2)
+	batch := p.Batch()
+	batch.Go(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return assert.AnError
+	})
+	batch.Go(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	err := batch.Wait()
+	assert.Error(t, err)
+	assert.Len(t, err.(MultiError).Errors(), 1)
+}
+
+type countingObserver struct {
+	submits, starts, finishes int32
+}
+
+func (o *countingObserver) OnSubmit()                      { atomic.AddInt32(&o.submits, 1) }
+func (o *countingObserver) OnStart()                       { atomic.AddInt32(&o.starts, 1) }
+func (o *countingObserver) OnFinish(err error)             { atomic.AddInt32(&o.finishes, 1) }
+func (o *countingObserver) OnWaitDuration(d time.Duration) {}
+
+func TestObserverAndStats(t *testing.T) {
+	obs := &countingObserver{}
+	pool := NewPool(2, WithObserver(obs))
+
+	batch := pool.Batch()
+	batch.Submit(func() error { return nil })
+	batch.Submit(func() error { return assert.AnError })
+	batch.Wait()
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&obs.submits))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&obs.starts))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&obs.finishes))
+
+	stats := pool.Stats()
+	assert.EqualValues(t, 2, stats.Submitted)
+	assert.EqualValues(t, 2, stats.Completed)
+	assert.EqualValues(t, 1, stats.Failed)
+	assert.Equal(t, 0, stats.Running)
+}
+
+type reentrantObserver struct {
+	pool    *Pool
+	started chan struct{}
+}
+
+func (o *reentrantObserver) OnSubmit()                      {}
+func (o *reentrantObserver) OnFinish(err error)             {}
+func (o *reentrantObserver) OnWaitDuration(d time.Duration) {}
+func (o *reentrantObserver) OnStart() {
+	//calling back into the Pool from a callback must not deadlock
+	o.pool.Stats()
+	close(o.started)
+}
+
+func TestObserverCallbackCanCallStats(t *testing.T) {
+	obs := &reentrantObserver{started: make(chan struct{})}
+	pool := NewPool(1, WithObserver(obs))
+	obs.pool = pool
+
+	pool.Submit(func() error { return nil })
+
+	select {
+	case <-obs.started:
+	case <-time.After(time.Second):
+		t.Fatal("OnStart calling pool.Stats() deadlocked")
+	}
+	pool.Stop()
+}
+
+func TestStopWaitsForQueuedJobs(t *testing.T) {
+	pool := NewPool(1)
+	started := make(chan struct{})
+	block := make(chan struct{})
+	pool.Submit(func() error {
+		close(started)
+		<-block
+		return nil
+	})
+	<-started
+
+	queuedStarted := make(chan struct{})
+	go pool.Submit(func() error {
+		close(queuedStarted)
+		return nil
+	})
+	//give the second Submit time to queue behind the first job
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	close(block)
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-queuedStarted:
+	case <-stopped:
+		t.Fatal("Stop returned before the queued job started")
+	}
+	<-stopped
+}
+
+func TestBatchGoCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool, _ := PoolWithContext(ctx, 1)
+	batch := pool.Batch()
+	batch.Go(func() error {
+		t.Fatal("job should not have run")
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		batch.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("batch.Wait() deadlocked waiting on a job that was never admitted")
+	}
+}
+
+func TestBatchUnadmittedJobsDontAddErrors(t *testing.T) {
+	pool := NewPool(1)
+	batch, _ := pool.BatchWithContext(context.Background())
+
+	//occupies the pool's only slot long enough for the Go calls below to
+	//queue behind it, then fails and cancels the batch's context
+	batch.SubmitCtx(func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return assert.AnError
+	})
+	//give SubmitCtx's job time to start and claim the only slot
+	time.Sleep(5 * time.Millisecond)
+
+	//none of these should ever run: by the time a slot frees up, the batch's
+	//context has already been canceled by the job above
+	for i := 0; i < 5; i++ {
+		batch.Go(func() error {
+			t.Fatal("job should not have run")
+			return nil
+		})
+	}
+
+	err := batch.Wait()
+	assert.Error(t, err)
+	//only the real failure should be reported, not a synthetic error for
+	//each of the 5 jobs that were never admitted
+	assert.Len(t, err.(MultiError).Errors(), 1)
+}
+
+func TestBatchWithContext(t *testing.T) {
+	pool := NewPool(2)
+	batch, ctx := pool.BatchWithContext(context.Background())
+
+	batch.SubmitCtx(func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return assert.AnError
+	})
+	batch.SubmitCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := batch.Wait()
+	assert.Error(t, err)
+	assert.Len(t, err.(MultiError).Errors(), 2)
+	assert.Equal(t, context.Canceled, ctx.Err())
+}